@@ -0,0 +1,45 @@
+package command
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/jxwr/cc/cli/context"
+	"github.com/jxwr/cc/frontend/api"
+	"github.com/jxwr/cc/utils"
+)
+
+var EventsCommand = cli.Command{
+	Name:   "events",
+	Usage:  "events <id>",
+	Action: eventsAction,
+}
+
+func eventsAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		fmt.Println(ErrInvalidParameter)
+		return
+	}
+	addr := context.GetLeaderAddr()
+	extraHeader := &utils.ExtraHeader{
+		User:  context.Config.User,
+		Role:  context.Config.Role,
+		Token: context.Config.Token,
+	}
+
+	nodeid, err := context.GetId(c.Args()[0])
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	url := "http://" + addr + api.EventLogPath + "?id=" + nodeid
+	resp, err := utils.HttpGetExtra(url, 5*time.Second, extraHeader)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	ShowResponse(resp)
+}
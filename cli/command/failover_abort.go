@@ -0,0 +1,48 @@
+package command
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/jxwr/cc/cli/context"
+	"github.com/jxwr/cc/frontend/api"
+	"github.com/jxwr/cc/utils"
+)
+
+var FailoverAbortCommand = cli.Command{
+	Name:   "failover-abort",
+	Usage:  "failover-abort <node-id>",
+	Action: failoverAbortAction,
+}
+
+func failoverAbortAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		fmt.Println(ErrInvalidParameter)
+		return
+	}
+	addr := context.GetLeaderAddr()
+	extraHeader := &utils.ExtraHeader{
+		User:  context.Config.User,
+		Role:  context.Config.Role,
+		Token: context.Config.Token,
+	}
+
+	url := "http://" + addr + api.FailoverAbortPath
+	nodeid, err := context.GetId(c.Args()[0])
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	req := api.FailoverAbortParams{
+		NodeId: nodeid,
+	}
+	resp, err := utils.HttpPostExtra(url, req, 5*time.Second, extraHeader)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	ShowResponse(resp)
+}
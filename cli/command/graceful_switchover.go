@@ -0,0 +1,61 @@
+package command
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/codegangsta/cli"
+
+	"github.com/jxwr/cc/cli/context"
+	"github.com/jxwr/cc/frontend/api"
+	"github.com/jxwr/cc/utils"
+)
+
+var GracefulSwitchoverCommand = cli.Command{
+	Name:   "graceful-switchover",
+	Usage:  "graceful-switchover <master-id> [--candidate <slave-id>]",
+	Flags: []cli.Flag{
+		cli.StringFlag{Name: "candidate", Value: "", Usage: "slave id to promote, default to the best ranked candidate"},
+	},
+	Action: gracefulSwitchoverAction,
+}
+
+func gracefulSwitchoverAction(c *cli.Context) {
+	if len(c.Args()) != 1 {
+		fmt.Println(ErrInvalidParameter)
+		return
+	}
+	addr := context.GetLeaderAddr()
+	extraHeader := &utils.ExtraHeader{
+		User:  context.Config.User,
+		Role:  context.Config.Role,
+		Token: context.Config.Token,
+	}
+
+	masterId, err := context.GetId(c.Args()[0])
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	candidateId := ""
+	if c.String("candidate") != "" {
+		candidateId, err = context.GetId(c.String("candidate"))
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	url := "http://" + addr + api.GracefulSwitchoverPath
+	req := api.GracefulSwitchoverParams{
+		MasterId:    masterId,
+		CandidateId: candidateId,
+	}
+	resp, err := utils.HttpPostExtra(url, req, 5*time.Second, extraHeader)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	ShowResponse(resp)
+}
@@ -0,0 +1,37 @@
+// Package api实现frontend暴露给cc-cli的管理接口，每个文件对应一组命令，
+// 声明其HTTP路径、请求参数，并把请求转发到state包里对应的能力上。
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/jxwr/cc/state"
+)
+
+// Cluster是frontend进程持有的当前集群状态，在controller启动时完成注入，
+// 各管理接口通过它访问NodeState/ClusterState
+var Cluster *state.ClusterState
+
+var errMissingNodeId = errors.New("missing required parameter: id")
+
+// Output是所有管理接口统一的响应包装，Code非0表示失败，Message携带出错原因
+type Output struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func decodeParams(r *http.Request, params interface{}) error {
+	defer r.Body.Close()
+	return json.NewDecoder(r.Body).Decode(params)
+}
+
+func writeOK(w http.ResponseWriter, data interface{}) {
+	json.NewEncoder(w).Encode(Output{Code: 0, Data: data})
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	json.NewEncoder(w).Encode(Output{Code: 1, Message: err.Error()})
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jxwr/cc/state"
+)
+
+// failoverConfirmProbeResponse镜像state包里发起方解析的响应结构
+type failoverConfirmProbeResponse struct {
+	Fail bool `json:"fail"`
+}
+
+// FailoverConfirmProbe是state.ConfirmDead在配置了meta.FailoverConfirmPeerAddrs()时
+// 向其它cc peer发起确认所请求的对端接口：每个cc进程都必须注册它，否则所有peer探测
+// 都会失败，quorum永远达不成，等于悄悄关闭了这些部署的master自动Failover
+func FailoverConfirmProbe(w http.ResponseWriter, r *http.Request) {
+	nodeId := r.URL.Query().Get("id")
+	if nodeId == "" {
+		writeError(w, errMissingNodeId)
+		return
+	}
+
+	ns := Cluster.FindNodeState(nodeId)
+	if ns == nil {
+		writeError(w, fmt.Errorf("node %s not found", nodeId))
+		return
+	}
+
+	json.NewEncoder(w).Encode(failoverConfirmProbeResponse{Fail: ns.IsFail()})
+}
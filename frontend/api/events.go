@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/jxwr/cc/state"
+)
+
+// EventLogPath是events命令对应的审计接口路径，按?id=<node-id>查询，
+// 可选?n=<count>限制返回条数(默认返回全部)
+const EventLogPath = "/api/failover/events"
+
+// EventLogResponse把两份独立的审计记录放在一起返回：Transitions是节点的FSM
+// 迁移历史，Events是pre/post-failover钩子的stdout/stderr(见state.RecordEvent)，
+// 后者此前只写入、从未被任何接口读出，排查钩子失败只能翻controller日志
+type EventLogResponse struct {
+	Transitions []state.FSMEventEntry `json:"transitions"`
+	Events      []state.ClusterEvent  `json:"events"`
+}
+
+// EventLog返回指定节点的FSM事件日志和钩子事件日志，用于审计以及排查controller
+// 重启后的恢复行为、以及pre/post-failover钩子的执行结果
+func EventLog(w http.ResponseWriter, r *http.Request) {
+	nodeId := r.URL.Query().Get("id")
+	if nodeId == "" {
+		writeError(w, errMissingNodeId)
+		return
+	}
+
+	n := 0
+	if s := r.URL.Query().Get("n"); s != "" {
+		if parsed, err := strconv.Atoi(s); err == nil {
+			n = parsed
+		}
+	}
+
+	transitions, err := state.TailFSMEventLog(nodeId, n)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeOK(w, EventLogResponse{
+		Transitions: transitions,
+		Events:      state.Events(nodeId),
+	})
+}
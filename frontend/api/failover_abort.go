@@ -0,0 +1,39 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jxwr/cc/state"
+)
+
+// FailoverAbortPath是failover-abort命令对应的接口路径
+const FailoverAbortPath = "/api/failover/abort"
+
+// FailoverAbortParams是failover-abort命令的请求参数
+type FailoverAbortParams struct {
+	NodeId string `json:"node_id"`
+}
+
+// FailoverAbort把一个停留在WAIT_FAILOVER_BEGIN的节点强制打回RUNNING，
+// 并清除它的宽限期和分布式确认缓存
+func FailoverAbort(w http.ResponseWriter, r *http.Request) {
+	var params FailoverAbortParams
+	if err := decodeParams(r, &params); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	ns := Cluster.FindNodeState(params.NodeId)
+	if ns == nil {
+		writeError(w, fmt.Errorf("node %s not found", params.NodeId))
+		return
+	}
+	if ns.CurrentState() != state.StateWaitFailoverBegin {
+		writeError(w, fmt.Errorf("node %s is not WAIT_FAILOVER_BEGIN, refuse to abort", params.NodeId))
+		return
+	}
+
+	ns.AdvanceFSM(Cluster, state.CMD_FAILOVER_ABORT_SIGNAL)
+	writeOK(w, nil)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jxwr/cc/state"
+)
+
+// FailoverAckPath是failover-ack命令对应的接口路径
+const FailoverAckPath = "/api/failover/ack"
+
+// FailoverAckParams是failover-ack命令的请求参数
+type FailoverAckParams struct {
+	NodeId string `json:"node_id"`
+}
+
+// FailoverAck为一个卡在WAIT_FAILOVER_END的节点手动发出CMD_FAILOVER_END_SIGNAL，
+// 用于Failover任务已经在外部确认完成、但信号丢失导致FSM卡住的场景
+func FailoverAck(w http.ResponseWriter, r *http.Request) {
+	var params FailoverAckParams
+	if err := decodeParams(r, &params); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	ns := Cluster.FindNodeState(params.NodeId)
+	if ns == nil {
+		writeError(w, fmt.Errorf("node %s not found", params.NodeId))
+		return
+	}
+	if ns.CurrentState() != state.StateWaitFailoverEnd {
+		writeError(w, fmt.Errorf("node %s is not WAIT_FAILOVER_END, refuse to ack", params.NodeId))
+		return
+	}
+
+	ns.AdvanceFSM(Cluster, state.CMD_FAILOVER_END_SIGNAL)
+	writeOK(w, nil)
+}
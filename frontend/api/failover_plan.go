@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/jxwr/cc/state"
+)
+
+// FailoverPlanPath是failover-plan命令对应的接口路径，不会真正执行Failover，
+// 只返回PromotionSelector排出的候选顺序，供运维事先核对
+const FailoverPlanPath = "/api/failover/plan"
+
+// FailoverPlanParams是failover-plan命令的请求参数
+type FailoverPlanParams struct {
+	NodeId string `json:"node_id"`
+}
+
+// FailoverPlan返回指定master当前的候选提升顺序，dry-run，不会触发任何Failover
+func FailoverPlan(w http.ResponseWriter, r *http.Request) {
+	var params FailoverPlanParams
+	if err := decodeParams(r, &params); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	candidates, err := state.NewPromotionSelector().Rank(Cluster, params.NodeId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeOK(w, candidates)
+}
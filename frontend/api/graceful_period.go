@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/jxwr/cc/state"
+)
+
+// ClearGracefulPeriodPath是clear-graceful-period命令对应的管理接口路径
+const ClearGracefulPeriodPath = "/api/failover/clear_graceful_period"
+
+// ClearGracefulPeriodParams是clear-graceful-period命令的请求参数
+type ClearGracefulPeriodParams struct {
+	NodeId string `json:"node_id"`
+}
+
+// ClearGracefulPeriod清除指定节点的EmergencyOperationGracefulPeriod，
+// 用于运维确认故障已处理、需要立即恢复该节点自动Failover能力的场景
+func ClearGracefulPeriod(w http.ResponseWriter, r *http.Request) {
+	var params ClearGracefulPeriodParams
+	if err := decodeParams(r, &params); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	state.ClearEmergencyOperationGracefulPeriod(params.NodeId)
+	writeOK(w, nil)
+}
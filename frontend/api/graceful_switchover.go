@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jxwr/cc/state"
+)
+
+// GracefulSwitchoverPath是graceful-switchover命令对应的接口路径
+const GracefulSwitchoverPath = "/api/failover/graceful_switchover"
+
+// GracefulSwitchoverTimeout是等待候选从节点追平master复制偏移量的超时时间
+const GracefulSwitchoverTimeout = 30 * time.Second
+
+// GracefulSwitchoverParams是graceful-switchover命令的请求参数，CandidateId为空
+// 表示使用PromotionSelector选出的最优候选
+type GracefulSwitchoverParams struct {
+	MasterId    string `json:"master_id"`
+	CandidateId string `json:"candidate_id"`
+}
+
+// GracefulSwitchover暂停master写入，等待候选从节点复制偏移量追平后直接提升，
+// 不触发FAIL驱动的FSM路径
+func GracefulSwitchover(w http.ResponseWriter, r *http.Request) {
+	var params GracefulSwitchoverParams
+	if err := decodeParams(r, &params); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := state.GracefulSwitchover(Cluster, params.MasterId, params.CandidateId, GracefulSwitchoverTimeout); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeOK(w, nil)
+}
@@ -0,0 +1,220 @@
+package state
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jxwr/cc/meta"
+	"github.com/jxwr/cc/redis"
+)
+
+// confirmDeadCacheEntry 缓存对某个可疑节点最近一次分布式确认的结果
+type confirmDeadCacheEntry struct {
+	result   bool
+	expireAt time.Time
+}
+
+var (
+	confirmDeadCacheMu  sync.Mutex
+	confirmDeadCache    = map[string]confirmDeadCacheEntry{}
+	confirmDeadInFlight = map[string]bool{}
+)
+
+// FailoverConfirmProbePath是cc controller之间互相确认可疑master时请求的路径，
+// 每个cc进程都应该在frontend/api里注册它，用本机视角独立判断目标节点是否FAIL
+const FailoverConfirmProbePath = "/api/failover/confirm_probe"
+
+// ConfirmDead 返回对该节点最近一次分布式确认的结论，只有在timeout内达到quorum个
+// 独立确认时才为true。
+//
+// 这个方法本身从不发起阻塞的网络IO：MasterAutoFailoverConstraint在持有FSM求值
+// 期间每个tick都会调用它一次，这个仓库里"放到另一个线程做，避免死锁"的既有约定
+// 意味着这条路径不能容忍阻塞式的网络往返。ConfirmDead只读取缓存；缓存未命中时，
+// 触发一次后台探测(如果还没有一个在飞行中)并立即返回false——在探测结果回来之前，
+// 保守地拒绝Failover，不阻塞调用方。真正的探测逻辑见confirmDeadViaPeerControllers/
+// confirmDeadViaRedisGossip，由triggerConfirmDeadAsync在独立goroutine里驱动。
+func (ns *NodeState) ConfirmDead(cs *ClusterState, quorum int, timeout time.Duration) bool {
+	if cached, ok := cachedConfirmDead(ns.Id()); ok {
+		return cached
+	}
+	triggerConfirmDeadAsync(ns, cs, quorum, timeout)
+	return false
+}
+
+// triggerConfirmDeadAsync在后台发起一次分布式确认，结果写入缓存供下一次ConfirmDead
+// 读取。同一节点同时只允许一次探测在飞行中，避免MasterAutoFailoverConstraint的
+// 高频重试把探测goroutine堆起来。
+//
+// 如果meta配置了其它cc controller的地址(FailoverConfirmPeerAddrs)，优先向这些
+// 独立进程发起确认：cc自身与master发生网络分区时，大概率也与同一网段内的redis
+// 节点处于同一侧分区，直接探测集群内redis节点对这种场景没有意义，只有部署在不同
+// 网络位置的cc peer才能给出真正独立的判断。没有配置peer时，退化为向集群内其它所有
+// redis节点直接探测，这仍然能防住"探测抖动造成的误判"这类较弱的场景。
+func triggerConfirmDeadAsync(ns *NodeState, cs *ClusterState, quorum int, timeout time.Duration) {
+	if !startConfirmDeadProbe(ns.Id()) {
+		return
+	}
+
+	go func() {
+		defer finishConfirmDeadProbe(ns.Id())
+
+		var result bool
+		if peerAddrs := meta.FailoverConfirmPeerAddrs(); len(peerAddrs) > 0 {
+			result = confirmDeadViaPeerControllers(ns, peerAddrs, quorum, timeout)
+		} else {
+			result = confirmDeadViaRedisGossip(ns, cs, quorum, timeout)
+		}
+		cacheConfirmDead(ns.Id(), result)
+	}()
+}
+
+// prewarmConfirmDead在节点刚进入WAIT_FAILOVER_BEGIN时提前触发一次后台确认探测，
+// 使用和MasterAutoFailoverConstraint里完全相同的quorum计算方式，这样缓存命中后
+// 两边得到的是同一个结果。如果该region节点数不足以计算quorum，这里什么都不做，
+// 交由constraint在真正求值时处理
+func prewarmConfirmDead(cs *ClusterState, ns *NodeState) {
+	rs := cs.FindReplicaSetByNode(ns.Id())
+	if rs == nil {
+		return
+	}
+	localRegionNodes := rs.RegionNodes(ns.node.Region)
+	quorum := meta.FailoverConfirmQuorum(len(localRegionNodes))
+	if quorum <= 0 {
+		return
+	}
+	triggerConfirmDeadAsync(ns, cs, quorum, meta.FailoverConfirmTimeout())
+}
+
+func startConfirmDeadProbe(nodeId string) bool {
+	confirmDeadCacheMu.Lock()
+	defer confirmDeadCacheMu.Unlock()
+	if confirmDeadInFlight[nodeId] {
+		return false
+	}
+	confirmDeadInFlight[nodeId] = true
+	return true
+}
+
+func finishConfirmDeadProbe(nodeId string) {
+	confirmDeadCacheMu.Lock()
+	defer confirmDeadCacheMu.Unlock()
+	delete(confirmDeadInFlight, nodeId)
+}
+
+// ClearConfirmDeadCache清除某个节点缓存的确认结果，在节点离开
+// WAIT_FAILOVER_BEGIN(恢复或被手动abort)时调用，避免一次陈旧的结论
+// 影响它之后重新进入该状态时的判断
+func ClearConfirmDeadCache(nodeId string) {
+	confirmDeadCacheMu.Lock()
+	defer confirmDeadCacheMu.Unlock()
+	delete(confirmDeadCache, nodeId)
+}
+
+func cachedConfirmDead(nodeId string) (bool, bool) {
+	confirmDeadCacheMu.Lock()
+	defer confirmDeadCacheMu.Unlock()
+	entry, ok := confirmDeadCache[nodeId]
+	if !ok || time.Now().After(entry.expireAt) {
+		return false, false
+	}
+	return entry.result, true
+}
+
+func cacheConfirmDead(nodeId string, result bool) {
+	confirmDeadCacheMu.Lock()
+	defer confirmDeadCacheMu.Unlock()
+	confirmDeadCache[nodeId] = confirmDeadCacheEntry{
+		result:   result,
+		expireAt: time.Now().Add(meta.FailoverConfirmCacheTTL()),
+	}
+}
+
+func confirmDeadViaRedisGossip(ns *NodeState, cs *ClusterState, quorum int, timeout time.Duration) bool {
+	var peers []string
+	for _, peer := range cs.AllNodeStates() {
+		if peer.Id() == ns.Id() {
+			continue
+		}
+		peers = append(peers, peer.Addr())
+	}
+	if len(peers) == 0 {
+		return true
+	}
+
+	result := make(chan bool, len(peers))
+	for _, addr := range peers {
+		go func(addr string) {
+			fail, err := redis.ProbeNodeFail(addr, ns.Id(), timeout)
+			if err != nil {
+				log.Printf("ConfirmDead: probe via %s for suspect %s failed: %v\n", addr, ns.Id(), err)
+				result <- false
+				return
+			}
+			result <- fail
+		}(addr)
+	}
+	return countConfirmations(result, len(peers), quorum, timeout, ns.Id())
+}
+
+func confirmDeadViaPeerControllers(ns *NodeState, peerAddrs []string, quorum int, timeout time.Duration) bool {
+	client := &http.Client{Timeout: timeout}
+	result := make(chan bool, len(peerAddrs))
+	for _, addr := range peerAddrs {
+		go func(addr string) {
+			fail, err := probePeerController(client, addr, ns.Id())
+			if err != nil {
+				log.Printf("ConfirmDead: probe via cc peer %s for suspect %s failed: %v\n", addr, ns.Id(), err)
+				result <- false
+				return
+			}
+			result <- fail
+		}(addr)
+	}
+	return countConfirmations(result, len(peerAddrs), quorum, timeout, ns.Id())
+}
+
+func countConfirmations(result chan bool, total, quorum int, timeout time.Duration, nodeId string) bool {
+	confirmed := 0
+	deadline := time.After(timeout)
+	for i := 0; i < total; i++ {
+		select {
+		case fail := <-result:
+			if fail {
+				confirmed++
+			}
+		case <-deadline:
+			log.Printf("ConfirmDead: timeout waiting for quorum on %s, got %d/%d confirmations\n",
+				nodeId, confirmed, quorum)
+			return confirmed >= quorum
+		}
+	}
+	return confirmed >= quorum
+}
+
+type peerConfirmResponse struct {
+	Fail bool `json:"fail"`
+}
+
+func probePeerController(client *http.Client, addr, nodeId string) (bool, error) {
+	url := "http://" + addr + FailoverConfirmProbePath + "?id=" + nodeId
+	resp, err := client.Get(url)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	var out peerConfirmResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return false, err
+	}
+	return out.Fail, nil
+}
+
+// IsFail返回本机视角下这个节点当前是否处于FAIL状态，供FailoverConfirmProbePath
+// 的HTTP handler使用，把本机独立的判断回复给发起确认的cc peer
+func (ns *NodeState) IsFail() bool {
+	return ns.node.Fail
+}
@@ -0,0 +1,83 @@
+package state
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCountConfirmationsReachesQuorum(t *testing.T) {
+	result := make(chan bool, 3)
+	result <- true
+	result <- true
+	result <- false
+
+	if !countConfirmations(result, 3, 2, time.Second, "node-1") {
+		t.Fatal("expected quorum of 2/3 confirmations to be reached")
+	}
+}
+
+func TestCountConfirmationsBelowQuorum(t *testing.T) {
+	result := make(chan bool, 3)
+	result <- true
+	result <- false
+	result <- false
+
+	if countConfirmations(result, 3, 2, time.Second, "node-1") {
+		t.Fatal("expected 1/3 confirmations to not reach a quorum of 2")
+	}
+}
+
+func TestCountConfirmationsTimesOutBelowQuorum(t *testing.T) {
+	result := make(chan bool, 3)
+	result <- true
+
+	if countConfirmations(result, 3, 2, 10*time.Millisecond, "node-1") {
+		t.Fatal("expected timeout with only 1 confirmation to not reach a quorum of 2")
+	}
+}
+
+func TestConfirmDeadCacheRoundTrip(t *testing.T) {
+	defer ClearConfirmDeadCache("node-3")
+
+	if _, ok := cachedConfirmDead("node-3"); ok {
+		t.Fatal("expected no cached result before one is set")
+	}
+
+	cacheConfirmDead("node-3", true)
+	result, ok := cachedConfirmDead("node-3")
+	if !ok || !result {
+		t.Fatal("expected cached confirmation to be true")
+	}
+
+	ClearConfirmDeadCache("node-3")
+	if _, ok := cachedConfirmDead("node-3"); ok {
+		t.Fatal("expected cache entry to be gone after ClearConfirmDeadCache")
+	}
+}
+
+// TestProbePeerControllerParsesConfirmResponse验证probePeerController能正确解析
+// frontend/api.FailoverConfirmProbe返回的JSON形状，这是ConfirmDead在配置了
+// meta.FailoverConfirmPeerAddrs()时唯一会走到的确认路径，必须和对端handler的
+// 响应格式保持一致，否则所有peer探测都会悄悄失败
+func TestProbePeerControllerParsesConfirmResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("id"); got != "node-x" {
+			t.Errorf("expected probe for id=node-x, got %q", got)
+		}
+		json.NewEncoder(w).Encode(map[string]bool{"fail": true})
+	}))
+	defer srv.Close()
+
+	addr := strings.TrimPrefix(srv.URL, "http://")
+	fail, err := probePeerController(http.DefaultClient, addr, "node-x")
+	if err != nil {
+		t.Fatalf("probePeerController: %v", err)
+	}
+	if !fail {
+		t.Fatal("expected fail=true decoded from peer response")
+	}
+}
@@ -0,0 +1,274 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// ClusterEvent 是记录在集群事件日志中的一条事件，目前只在内存中保存，
+// 主要用于记录Failover各阶段外部钩子的执行结果，供调试和审计使用
+type ClusterEvent struct {
+	NodeId    string
+	Message   string
+	Timestamp time.Time
+}
+
+var (
+	eventLogMu sync.Mutex
+	eventLog   []ClusterEvent
+)
+
+// RecordEvent 向集群事件日志追加一条记录
+func RecordEvent(nodeId, message string) {
+	eventLogMu.Lock()
+	defer eventLogMu.Unlock()
+	eventLog = append(eventLog, ClusterEvent{
+		NodeId:    nodeId,
+		Message:   message,
+		Timestamp: time.Now(),
+	})
+}
+
+// Events 返回指定节点的事件日志
+func Events(nodeId string) []ClusterEvent {
+	eventLogMu.Lock()
+	defer eventLogMu.Unlock()
+	var result []ClusterEvent
+	for _, e := range eventLog {
+		if e.NodeId == nodeId {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// FSMEventEntry 记录一次FSM迁移的完整信息，持久化下来用于审计以及
+// controller重启后的故障恢复重放
+type FSMEventEntry struct {
+	NodeId    string    `json:"node_id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Input     string    `json:"input"`
+	Outcome   string    `json:"outcome"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// FSMEventLogBackend 是FSM事件日志的存储后端，可以是本地文件(fsync)，
+// 也可以换成Zookeeper/etcd之类的强一致存储
+type FSMEventLogBackend interface {
+	Append(entry FSMEventEntry) error
+	All() ([]FSMEventEntry, error)
+	Tail(nodeId string, n int) ([]FSMEventEntry, error)
+}
+
+// LocalFileFSMEventLog是默认的事件日志实现，以追加写文件+fsync的方式持久化
+type LocalFileFSMEventLog struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+}
+
+func NewLocalFileFSMEventLog(path string) (*LocalFileFSMEventLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalFileFSMEventLog{path: path, f: f}, nil
+}
+
+func (l *LocalFileFSMEventLog) Append(entry FSMEventEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := l.f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return l.f.Sync()
+}
+
+func (l *LocalFileFSMEventLog) All() ([]FSMEventEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []FSMEventEntry
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var entry FSMEventEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (l *LocalFileFSMEventLog) Tail(nodeId string, n int) ([]FSMEventEntry, error) {
+	all, err := l.All()
+	if err != nil {
+		return nil, err
+	}
+	var matched []FSMEventEntry
+	for _, e := range all {
+		if e.NodeId == nodeId {
+			matched = append(matched, e)
+		}
+	}
+	if n > 0 && len(matched) > n {
+		matched = matched[len(matched)-n:]
+	}
+	return matched, nil
+}
+
+var activeFSMEventLog FSMEventLogBackend
+
+// SetFSMEventLogBackend 注册FSM事件日志后端。本包只提供LocalFileFSMEventLog
+// 一种实现，InitFSMEventLog注册的总是它；这里没有Zookeeper/etcd实现
+func SetFSMEventLogBackend(backend FSMEventLogBackend) {
+	activeFSMEventLog = backend
+}
+
+// TailFSMEventLog返回指定节点最近n条FSM事件，n<=0表示返回全部，供
+// frontend/api的审计接口和cc-cli events使用。日志后端未初始化时返回空结果
+func TailFSMEventLog(nodeId string, n int) ([]FSMEventEntry, error) {
+	if activeFSMEventLog == nil {
+		return nil, nil
+	}
+	return activeFSMEventLog.Tail(nodeId, n)
+}
+
+var (
+	lastStateMu sync.Mutex
+	lastState   = map[string]string{}
+)
+
+// pendingOutcome缓存每个节点下一次LogStateEnter应该记录的真实Apply结果，
+// 由MasterFailoverHandler在pre-hook中止、候选耗尽、每次尝试提升之前、全部失败时写入，
+// 消费一次即清除。没有人写入时，outcome退化为默认的"applied"，
+// 对应(a0)/(b0)/(d0)这类没有自定义Apply、必然成功的迁移
+var (
+	pendingOutcomeMu sync.Mutex
+	pendingOutcome   = map[string]string{}
+)
+
+// RecordFailoverOutcome记录一次Failover实际发生的结果，供随后触发的状态迁移
+// 在写入FSM事件日志时使用，而不是笼统地记成"applied"
+func RecordFailoverOutcome(nodeId, outcome string) {
+	pendingOutcomeMu.Lock()
+	defer pendingOutcomeMu.Unlock()
+	pendingOutcome[nodeId] = outcome
+}
+
+func takePendingOutcome(nodeId string) string {
+	pendingOutcomeMu.Lock()
+	defer pendingOutcomeMu.Unlock()
+	outcome, ok := pendingOutcome[nodeId]
+	if !ok {
+		return "applied"
+	}
+	delete(pendingOutcome, nodeId)
+	return outcome
+}
+
+// LogStateEnter 在每个State的OnEnter回调中调用，记录一次FSM迁移
+func LogStateEnter(ctx StateContext, to string) {
+	if activeFSMEventLog == nil {
+		return
+	}
+
+	nodeId := ctx.NodeState.Id()
+	lastStateMu.Lock()
+	from := lastState[nodeId]
+	lastState[nodeId] = to
+	lastStateMu.Unlock()
+
+	entry := FSMEventEntry{
+		NodeId:    nodeId,
+		From:      from,
+		To:        to,
+		Input:     fmt.Sprintf("%+v", ctx.Input),
+		Outcome:   takePendingOutcome(nodeId),
+		Timestamp: time.Now(),
+	}
+	if err := activeFSMEventLog.Append(entry); err != nil {
+		log.Printf("Failed to append FSM event log for %s: %v\n", nodeId, err)
+	}
+}
+
+// ReplayFSMEventLog 在controller启动时重放事件日志尾部，重建进行中的Failover状态。
+// 如果日志显示某节点最后进入了WAIT_FAILOVER_END但没有匹配的CMD_FAILOVER_END_SIGNAL，
+// controller恢复该节点的Failover任务，而不是简单地把它当成从RUNNING状态开始。
+func ReplayFSMEventLog(cs *ClusterState) {
+	if activeFSMEventLog == nil {
+		return
+	}
+	all, err := activeFSMEventLog.All()
+	if err != nil {
+		log.Printf("Failed to replay FSM event log: %v\n", err)
+		return
+	}
+
+	for _, nodeId := range nodesNeedingFailoverResume(all) {
+		ns := cs.FindNodeState(nodeId)
+		if ns == nil {
+			continue
+		}
+
+		ctx := StateContext{ClusterState: cs, NodeState: ns}
+		candidate, err := ActiveFailoverPolicy.SelectPromotionCandidate(ctx, nil)
+		if err != nil {
+			log.Printf("Cannot resume failover for %s after restart: %v\n", nodeId, err)
+			continue
+		}
+		log.Printf("Resuming failover for %s after controller restart, promoting %s\n", nodeId, candidate)
+		go cs.RunFailoverTask(nodeId, candidate)
+	}
+}
+
+// nodesNeedingFailoverResume从日志的尾部状态里挑出那些最后一次迁移进入了
+// WAIT_FAILOVER_END、但日志里再没有出现后续迁移(意味着controller在等到
+// CMD_FAILOVER_END_SIGNAL之前就重启了)的节点，这些节点需要在启动时恢复Failover
+// 任务，而不是被当成从RUNNING重新开始
+func nodesNeedingFailoverResume(entries []FSMEventEntry) []string {
+	lastByNode := map[string]FSMEventEntry{}
+	for _, e := range entries {
+		lastByNode[e.NodeId] = e
+	}
+
+	var nodeIds []string
+	for nodeId, last := range lastByNode {
+		if last.To == StateWaitFailoverEnd {
+			nodeIds = append(nodeIds, nodeId)
+		}
+	}
+	return nodeIds
+}
+
+// InitFSMEventLog 注册一个本地文件FSM事件日志后端，并立即重放它的尾部来恢复
+// 重启前进行中的Failover任务。controller启动流程应当在加载完ClusterState之后、
+// 开始驱动FSM之前调用一次这个函数，否则LogStateEnter是永久的no-op，
+// "crash-safe across controller restarts"这个目标就无从谈起
+func InitFSMEventLog(path string, cs *ClusterState) error {
+	backend, err := NewLocalFileFSMEventLog(path)
+	if err != nil {
+		return fmt.Errorf("open FSM event log %s: %v", path, err)
+	}
+	SetFSMEventLogBackend(backend)
+	ReplayFSMEventLog(cs)
+	return nil
+}
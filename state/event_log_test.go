@@ -0,0 +1,76 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalFileFSMEventLogAppendAndTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fsm_events.log")
+	backend, err := NewLocalFileFSMEventLog(path)
+	if err != nil {
+		t.Fatalf("NewLocalFileFSMEventLog: %v", err)
+	}
+
+	entries := []FSMEventEntry{
+		{NodeId: "node-1", From: StateRunning, To: StateWaitFailoverBegin},
+		{NodeId: "node-1", From: StateWaitFailoverBegin, To: StateWaitFailoverEnd},
+		{NodeId: "node-2", From: StateRunning, To: StateOffline},
+	}
+	for _, e := range entries {
+		if err := backend.Append(e); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	all, err := backend.All()
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(all))
+	}
+
+	tail, err := backend.Tail("node-1", 1)
+	if err != nil {
+		t.Fatalf("Tail: %v", err)
+	}
+	if len(tail) != 1 || tail[0].To != StateWaitFailoverEnd {
+		t.Fatalf("expected last node-1 entry to be WAIT_FAILOVER_END, got %+v", tail)
+	}
+}
+
+func TestNodesNeedingFailoverResume(t *testing.T) {
+	entries := []FSMEventEntry{
+		{NodeId: "node-1", From: StateRunning, To: StateWaitFailoverBegin},
+		{NodeId: "node-1", From: StateWaitFailoverBegin, To: StateWaitFailoverEnd},
+		{NodeId: "node-2", From: StateRunning, To: StateWaitFailoverBegin},
+		{NodeId: "node-2", From: StateWaitFailoverBegin, To: StateWaitFailoverEnd},
+		{NodeId: "node-2", From: StateWaitFailoverEnd, To: StateOffline},
+	}
+
+	resume := nodesNeedingFailoverResume(entries)
+	if len(resume) != 1 || resume[0] != "node-1" {
+		t.Fatalf("expected only node-1 to need failover resume, got %v", resume)
+	}
+}
+
+func TestRecordFailoverOutcomeConsumedOnce(t *testing.T) {
+	defer func() {
+		pendingOutcomeMu.Lock()
+		delete(pendingOutcome, "node-3")
+		pendingOutcomeMu.Unlock()
+	}()
+
+	if got := takePendingOutcome("node-3"); got != "applied" {
+		t.Fatalf("expected default outcome 'applied', got %q", got)
+	}
+
+	RecordFailoverOutcome("node-3", "aborted: pre-failover hook rejected failover")
+	if got := takePendingOutcome("node-3"); got != "aborted: pre-failover hook rejected failover" {
+		t.Fatalf("expected recorded outcome, got %q", got)
+	}
+	if got := takePendingOutcome("node-3"); got != "applied" {
+		t.Fatalf("expected outcome to be consumed after one read, got %q", got)
+	}
+}
@@ -0,0 +1,123 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"time"
+
+	"github.com/jxwr/cc/meta"
+)
+
+// FailoverResult 描述一次Failover执行的最终结果，传递给FailoverPolicy.PostFailover
+type FailoverResult struct {
+	NodeId      string
+	CandidateId string
+	Success     bool
+	Err         error
+}
+
+// FailoverPolicy 把Failover各阶段的决策从MasterFailoverHandler中抽取出来，使得运维方
+// 可以注册自定义实现，在不修改cc代码的前提下接入DNS更新、VIP漂移、代理reload、报警等
+// 外部系统，类似orchestrator的pre/post-failover-processes钩子。
+// SlaveFailoverHandler不经过FailoverPolicy，从节点Failover不会执行这些钩子。
+type FailoverPolicy interface {
+	// PreFailover 在真正执行Failover前调用，返回error会中止本次Failover
+	PreFailover(ctx StateContext) error
+	// SelectPromotionCandidate 选择用于提升的从节点，excluded列出本轮Failover里
+	// 已经尝试过且提升失败的节点，实现应跳过它们，返回下一个候选，excluded为nil
+	// 或空表示这是本轮的第一次选择
+	SelectPromotionCandidate(ctx StateContext, excluded map[string]bool) (nodeId string, err error)
+	// PostFailover 在Failover结束后调用，无论成功与否，result.Err非空表示失败；
+	// 被PreFailover返回的error中止的Failover不会调用它
+	PostFailover(ctx StateContext, result FailoverResult)
+}
+
+// DefaultFailoverPolicy是cc内置的Failover策略，按PromotionSelector的排序选择候选，
+// 排序失败或没有候选时退回MaxReploffSlibing；
+// 同时在meta中配置了外部钩子时，在pre/post阶段执行对应的进程/webhook
+type DefaultFailoverPolicy struct {
+}
+
+func NewDefaultFailoverPolicy() *DefaultFailoverPolicy {
+	return &DefaultFailoverPolicy{}
+}
+
+func (p *DefaultFailoverPolicy) PreFailover(ctx StateContext) error {
+	hook := meta.PreFailoverHook()
+	if hook == "" {
+		return nil
+	}
+	return runFailoverHook(ctx, "pre-failover", hook, meta.AbortFailoverOnHookError())
+}
+
+func (p *DefaultFailoverPolicy) SelectPromotionCandidate(ctx StateContext, excluded map[string]bool) (string, error) {
+	cs := ctx.ClusterState
+	ns := ctx.NodeState
+
+	candidates, err := NewPromotionSelector().Rank(cs, ns.Id())
+	if err != nil || len(candidates) == 0 {
+		nodeId, err := cs.MaxReploffSlibing(ns.Id(), true)
+		if err == nil && excluded[nodeId] {
+			return "", fmt.Errorf("no promotion candidate left for %s after excluding %d failed attempt(s)", ns.Id(), len(excluded))
+		}
+		return nodeId, err
+	}
+	for _, candidate := range candidates {
+		if excluded[candidate.NodeId] {
+			continue
+		}
+		return candidate.NodeId, nil
+	}
+	return "", fmt.Errorf("no promotion candidate left for %s after excluding %d failed attempt(s)", ns.Id(), len(excluded))
+}
+
+func (p *DefaultFailoverPolicy) PostFailover(ctx StateContext, result FailoverResult) {
+	hook := meta.PostFailoverHook()
+	if hook == "" {
+		return
+	}
+	if err := runFailoverHook(ctx, "post-failover", hook, false); err != nil {
+		log.Printf("post-failover hook failed for %s: %v\n", result.NodeId, err)
+	}
+}
+
+// runFailoverHook 执行配置的外部进程/webhook钩子，把stdout/stderr记录到集群事件日志，
+// abortOnFail为true时，钩子非0退出将导致本次Failover被中止。
+// 钩子受meta.FailoverHookTimeout()限制执行时长，避免一个挂死的webhook把FSM迁移永久卡住。
+func runFailoverHook(ctx StateContext, stage, command string, abortOnFail bool) error {
+	ns := ctx.NodeState
+
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), meta.FailoverHookTimeout())
+	defer cancel()
+
+	cmd := exec.CommandContext(timeoutCtx, "sh", "-c", command)
+	cmd.Env = append(cmd.Env, fmt.Sprintf("CC_NODE_ID=%s", ns.Id()), fmt.Sprintf("CC_STAGE=%s", stage))
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	if timeoutCtx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s: %v", meta.FailoverHookTimeout(), err)
+	}
+	RecordEvent(ns.Id(), fmt.Sprintf("%s hook %q exited in %s, stdout=%q stderr=%q, err=%v",
+		stage, command, time.Since(start), stdout.String(), stderr.String(), err))
+
+	if err != nil && abortOnFail {
+		return fmt.Errorf("%s hook failed: %v", stage, err)
+	}
+	return nil
+}
+
+// ActiveFailoverPolicy是当前生效的Failover策略，默认为DefaultFailoverPolicy
+var ActiveFailoverPolicy FailoverPolicy = NewDefaultFailoverPolicy()
+
+// RegisterFailoverPolicy 替换当前生效的Failover策略
+func RegisterFailoverPolicy(p FailoverPolicy) {
+	ActiveFailoverPolicy = p
+}
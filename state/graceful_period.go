@@ -0,0 +1,56 @@
+package state
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jxwr/cc/meta"
+)
+
+// gracefulPeriodEntry 记录一个节点进入EmergencyOperationGracefulPeriod的截止时间
+type gracefulPeriodEntry struct {
+	expireAt time.Time
+}
+
+var (
+	gracefulPeriodMu sync.Mutex
+	gracefulPeriods  = map[string]gracefulPeriodEntry{}
+)
+
+// enterEmergencyOperationGracefulPeriod 把节点放入宽限期缓存，宽限期内
+// Master/SlaveAutoFailoverConstraint都会拒绝该节点的Failover，用来避免
+// 探测抖动（比如网络瞬断）反复触发WAIT_FAILOVER_BEGIN造成的Failover风暴。
+// 宽限期时长由meta配置，<=0表示不开启。
+func enterEmergencyOperationGracefulPeriod(nodeId string) {
+	period := meta.EmergencyOperationGracefulPeriod()
+	if period <= 0 {
+		return
+	}
+	gracefulPeriodMu.Lock()
+	defer gracefulPeriodMu.Unlock()
+	gracefulPeriods[nodeId] = gracefulPeriodEntry{expireAt: time.Now().Add(period)}
+}
+
+// isInEmergencyOperationGracefulPeriod 判断节点当前是否处于宽限期内，
+// 过期的记录在这里被懒清除，不需要单独的sweeper
+func isInEmergencyOperationGracefulPeriod(nodeId string) bool {
+	gracefulPeriodMu.Lock()
+	defer gracefulPeriodMu.Unlock()
+	entry, ok := gracefulPeriods[nodeId]
+	if !ok {
+		return false
+	}
+	if time.Now().After(entry.expireAt) {
+		delete(gracefulPeriods, nodeId)
+		return false
+	}
+	return true
+}
+
+// ClearEmergencyOperationGracefulPeriod 供管理员手动清除某个节点的宽限期，
+// 通过frontend/api暴露为管理接口，用于人工确认故障已处理、需要立即恢复Failover能力的场景
+func ClearEmergencyOperationGracefulPeriod(nodeId string) {
+	gracefulPeriodMu.Lock()
+	defer gracefulPeriodMu.Unlock()
+	delete(gracefulPeriods, nodeId)
+}
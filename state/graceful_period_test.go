@@ -0,0 +1,47 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsInEmergencyOperationGracefulPeriodLazyExpiry(t *testing.T) {
+	defer func() {
+		gracefulPeriodMu.Lock()
+		delete(gracefulPeriods, "node-1")
+		gracefulPeriodMu.Unlock()
+	}()
+
+	gracefulPeriodMu.Lock()
+	gracefulPeriods["node-1"] = gracefulPeriodEntry{expireAt: time.Now().Add(10 * time.Millisecond)}
+	gracefulPeriodMu.Unlock()
+
+	if !isInEmergencyOperationGracefulPeriod("node-1") {
+		t.Fatal("expected node-1 to be within its graceful period")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if isInEmergencyOperationGracefulPeriod("node-1") {
+		t.Fatal("expected node-1's graceful period to have lazily expired")
+	}
+
+	gracefulPeriodMu.Lock()
+	_, stillPresent := gracefulPeriods["node-1"]
+	gracefulPeriodMu.Unlock()
+	if stillPresent {
+		t.Fatal("expected expired entry to be removed from the map on lookup")
+	}
+}
+
+func TestClearEmergencyOperationGracefulPeriod(t *testing.T) {
+	gracefulPeriodMu.Lock()
+	gracefulPeriods["node-2"] = gracefulPeriodEntry{expireAt: time.Now().Add(time.Hour)}
+	gracefulPeriodMu.Unlock()
+
+	ClearEmergencyOperationGracefulPeriod("node-2")
+
+	if isInEmergencyOperationGracefulPeriod("node-2") {
+		t.Fatal("expected node-2's graceful period to be cleared")
+	}
+}
@@ -0,0 +1,57 @@
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jxwr/cc/redis"
+)
+
+// GracefulSwitchover 在不触发FAIL驱动的FSM路径的情况下，把master的角色平滑切换给
+// 一个健康的从节点：暂停master的写入，等待候选从节点的复制偏移量追平master，
+// 然后直接执行提升。要求master本身处于StateRunning。
+//
+// candidateId为空时，使用PromotionSelector选出的最优候选。
+func GracefulSwitchover(cs *ClusterState, masterId, candidateId string, timeout time.Duration) error {
+	master := cs.FindNodeState(masterId)
+	if master == nil {
+		return fmt.Errorf("node %s not found", masterId)
+	}
+	if master.CurrentState() != StateRunning {
+		return fmt.Errorf("node %s is not RUNNING, refuse graceful switchover", masterId)
+	}
+
+	if candidateId == "" {
+		candidates, err := NewPromotionSelector().Rank(cs, masterId)
+		if err != nil || len(candidates) == 0 {
+			return fmt.Errorf("no candidate available for graceful switchover of %s", masterId)
+		}
+		candidateId = candidates[0].NodeId
+	}
+	if cs.FindNodeState(candidateId) == nil {
+		return fmt.Errorf("candidate %s not found", candidateId)
+	}
+
+	if err := redis.PauseWrites(master.Addr()); err != nil {
+		return fmt.Errorf("pause writes on %s failed: %v", masterId, err)
+	}
+	defer redis.ResumeWrites(master.Addr())
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		masterOffset, err := cs.ReplOffsetOf(masterId)
+		if err != nil {
+			return fmt.Errorf("read offset of %s failed: %v", masterId, err)
+		}
+		candidateOffset, err := cs.ReplOffsetOf(candidateId)
+		if err != nil {
+			return fmt.Errorf("read offset of %s failed: %v", candidateId, err)
+		}
+		if candidateOffset >= masterOffset {
+			RecordEvent(masterId, fmt.Sprintf("graceful switchover to %s, replication offset caught up", candidateId))
+			return cs.RunFailoverTask(masterId, candidateId)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("candidate %s did not catch up with %s within %s", candidateId, masterId, timeout)
+}
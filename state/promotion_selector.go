@@ -0,0 +1,105 @@
+package state
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/jxwr/cc/meta"
+)
+
+// 优先级权重，从低到高排列，保证硬性规则(must-promote/must-not-promote、偏好Region)
+// 总是优先于复制偏移量和运行状态这类软性指标
+const (
+	scoreRunning         int64 = 1 << 10
+	scorePreferredRegion int64 = 1 << 20
+	scoreMustPromote     int64 = 1 << 30
+
+	// maxOffsetScore是复制偏移量能贡献的打分上限，严格小于scoreRunning。
+	// 繁忙的master的复制偏移量常年是几亿到几十亿字节，如果直接累加到score里，
+	// 会反超本该硬性优先的region/must-promote候选，所以必须先压缩到这个上限以内，
+	// 只用来在同一档位内部打破平局
+	maxOffsetScore int64 = scoreRunning - 1
+)
+
+// PromotionCandidate 是一次候选打分的结果，按Score从高到低表示提升优先级
+type PromotionCandidate struct {
+	NodeId string
+	Score  int64
+	Reason string
+}
+
+// PromotionSelector 根据偏好地域、运维打的must-promote/must-not-promote标签、
+// 复制偏移量和节点当前是否处于StateRunning，对候选从节点排序，
+// 取代原来纯粹按MaxReploffSlibing选主的逻辑
+type PromotionSelector struct {
+}
+
+func NewPromotionSelector() *PromotionSelector {
+	return &PromotionSelector{}
+}
+
+// Rank 返回master的候选从节点列表，按提升优先级从高到低排序
+func (s *PromotionSelector) Rank(cs *ClusterState, masterId string) ([]PromotionCandidate, error) {
+	rs := cs.FindReplicaSetByNode(masterId)
+	if rs == nil {
+		return nil, fmt.Errorf("no replicaset found for %s", masterId)
+	}
+
+	var candidates []PromotionCandidate
+	for _, node := range rs.Nodes() {
+		if node.Id == masterId {
+			continue
+		}
+
+		rule := meta.PromotionRuleOf(node.Id)
+		if rule == meta.PromotionRuleMustNotPromote {
+			continue
+		}
+
+		var score int64
+		reason := ""
+		if rule == meta.PromotionRuleMustPromote {
+			score += scoreMustPromote
+			reason = "must-promote"
+		}
+		if meta.PreferredRegion(masterId) == node.Region {
+			score += scorePreferredRegion
+			reason += "+preferred-region"
+		}
+
+		ns := cs.FindNodeState(node.Id)
+		if ns != nil && ns.CurrentState() == StateRunning {
+			score += scoreRunning
+		}
+
+		offset, err := cs.ReplOffsetOf(node.Id)
+		if err == nil {
+			score += normalizeOffsetScore(offset)
+		}
+
+		candidates = append(candidates, PromotionCandidate{NodeId: node.Id, Score: score, Reason: reason})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates, nil
+}
+
+// normalizeOffsetScore把原始的复制偏移量字节数压缩成一个不超过maxOffsetScore的打分，
+// 按其位长度(近似log2)映射，偏移量越大打分越高，但不会超出硬性规则的权重范围。
+//
+// offset是非负的int64，bits.Len64的结果最多为63(math.MaxInt64的位长度)，天然小于
+// maxOffsetScore(1023)，下面的clamp在当前输入范围内永远不会触发，只是为了在
+// maxOffsetScore将来被调小时仍然是一个正确的上限，不依赖"offset恰好是int64"这个事实
+func normalizeOffsetScore(offset int64) int64 {
+	if offset <= 0 {
+		return 0
+	}
+	score := int64(bits.Len64(uint64(offset)))
+	if score > maxOffsetScore {
+		return maxOffsetScore
+	}
+	return score
+}
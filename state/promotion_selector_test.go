@@ -0,0 +1,29 @@
+package state
+
+import "testing"
+
+func TestNormalizeOffsetScoreIsBoundedAndMonotonic(t *testing.T) {
+	if got := normalizeOffsetScore(0); got != 0 {
+		t.Fatalf("expected score 0 for non-positive offset, got %d", got)
+	}
+
+	small := normalizeOffsetScore(1 << 10)
+	large := normalizeOffsetScore(1 << 40)
+	if !(small < large) {
+		t.Fatalf("expected normalized score to increase with offset, got small=%d large=%d", small, large)
+	}
+	if large >= scoreRunning {
+		t.Fatalf("expected normalized offset score to stay below scoreRunning, got %d", large)
+	}
+
+	// offset是非负int64，bits.Len64对这类输入的最大可能返回值是63
+	// (math.MaxInt64的位长度)，远小于maxOffsetScore(1023)，所以合法输入
+	// 永远不会触发normalizeOffsetScore里的clamp分支
+	huge := normalizeOffsetScore(1 << 62)
+	if huge != 63 {
+		t.Fatalf("expected huge offset's bit-length score to be 63, got %d", huge)
+	}
+	if huge >= maxOffsetScore {
+		t.Fatalf("expected huge offset score to stay below maxOffsetScore(%d), got %d", maxOffsetScore, huge)
+	}
+}
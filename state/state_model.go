@@ -1,6 +1,7 @@
 package state
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/jxwr/cc/fsm"
@@ -15,11 +16,17 @@ const (
 	StateOffline           = "OFFLINE"
 )
 
+// CMD_FAILOVER_ABORT_SIGNAL是failover-abort命令的信号，和
+// CMD_FAILOVER_BEGIN_SIGNAL/CMD_FAILOVER_END_SIGNAL一起构成手动干预Failover
+// 用到的Command集合，用来把一个停留在WAIT_FAILOVER_BEGIN的节点强制打回RUNNING
+const CMD_FAILOVER_ABORT_SIGNAL = "failover-abort-signal"
+
 var (
 	RunningState = &fsm.State{
 		Name: StateRunning,
-		OnEnter: func(ctx interface{}) {
+		OnEnter: func(i interface{}) {
 			log.Println("Enter RUNNING state")
+			LogStateEnter(i.(StateContext), StateRunning)
 		},
 		OnLeave: func(ctx interface{}) {
 			log.Println("Leave RUNNING state")
@@ -28,8 +35,15 @@ var (
 
 	WaitFailoverBeginState = &fsm.State{
 		Name: StateWaitFailoverBegin,
-		OnEnter: func(ctx interface{}) {
+		OnEnter: func(i interface{}) {
 			log.Println("Enter WAIT_FAILOVER_BEGIN state")
+			ctx := i.(StateContext)
+			enterEmergencyOperationGracefulPeriod(ctx.NodeState.Id())
+			// 节点一进入WAIT_FAILOVER_BEGIN就提前触发一次分布式确认探测，这样
+			// MasterAutoFailoverConstraint第一次求值时大概率已经有缓存结果可用，
+			// 缩短"缓存未命中、保守拒绝Failover"的窗口
+			prewarmConfirmDead(ctx.ClusterState, ctx.NodeState)
+			LogStateEnter(ctx, StateWaitFailoverBegin)
 		},
 		OnLeave: func(ctx interface{}) {
 			log.Println("Leave WAIT_FAILOVER_BEGIN state")
@@ -38,8 +52,9 @@ var (
 
 	WaitFailoverEndState = &fsm.State{
 		Name: StateWaitFailoverEnd,
-		OnEnter: func(ctx interface{}) {
+		OnEnter: func(i interface{}) {
 			log.Println("Enter WAIT_FAILOVER_END state")
+			LogStateEnter(i.(StateContext), StateWaitFailoverEnd)
 		},
 		OnLeave: func(ctx interface{}) {
 			log.Println("Leave WAIT_FAILOVER_END state")
@@ -48,8 +63,9 @@ var (
 
 	OfflineState = &fsm.State{
 		Name: StateOffline,
-		OnEnter: func(ctx interface{}) {
+		OnEnter: func(i interface{}) {
 			log.Println("Enter OFFLINE state")
+			LogStateEnter(i.(StateContext), StateOffline)
 		},
 		OnLeave: func(ctx interface{}) {
 			log.Println("Leave OFFLINE state")
@@ -65,6 +81,12 @@ var (
 		cs := ctx.ClusterState
 		ns := ctx.NodeState
 
+		// 处于EmergencyOperationGracefulPeriod内的节点，拒绝Failover，避免抖动造成的
+		// Failover风暴
+		if isInEmergencyOperationGracefulPeriod(ns.Id()) {
+			return false
+		}
+
 		rs := cs.FindReplicaSetByNode(ns.Id())
 		if rs == nil {
 			return false
@@ -98,6 +120,12 @@ var (
 			return false
 		}
 
+		// 处于EmergencyOperationGracefulPeriod内的节点，拒绝Failover，避免抖动造成的
+		// Failover风暴
+		if isInEmergencyOperationGracefulPeriod(ns.Id()) {
+			return false
+		}
+
 		rs := cs.FindReplicaSetByNode(ns.Id())
 		if rs == nil {
 			return false
@@ -117,6 +145,14 @@ var (
 				return false
 			}
 		}
+		// 在真正放行之前，向集群内其它节点发起一次分布式确认，避免cc自身与master
+		// 网络分区、实际上master仍然存活时发生误判Failover
+		quorum := meta.FailoverConfirmQuorum(len(localRegionNodes))
+		if quorum > 0 && !ns.ConfirmDead(cs, quorum, meta.FailoverConfirmTimeout()) {
+			log.Println("Failover confirmation quorum not reached, refuse failover for", ns.Id())
+			return false
+		}
+
 		log.Println("Can do failover for master")
 		return true
 	}
@@ -139,13 +175,56 @@ var (
 		ctx := i.(StateContext)
 		cs := ctx.ClusterState
 		ns := ctx.NodeState
-		masterId, err := cs.MaxReploffSlibing(ns.Id(), true)
+
+		if err := ActiveFailoverPolicy.PreFailover(ctx); err != nil {
+			log.Printf("Pre-failover hook aborted failover for %s: %v\n", ns.Id(), err)
+			RecordFailoverOutcome(ns.Id(), fmt.Sprintf("aborted: pre-failover hook rejected failover: %v", err))
+			// 放到另一个线程做，避免死锁
+			go ns.AdvanceFSM(cs, CMD_FAILOVER_END_SIGNAL)
+			return
+		}
+
+		candidate, err := ActiveFailoverPolicy.SelectPromotionCandidate(ctx, nil)
 		if err != nil {
 			log.Printf("No slave can be used for failover %s\n", ns.Id())
+			RecordFailoverOutcome(ns.Id(), fmt.Sprintf("aborted: no promotion candidate available: %v", err))
+			ActiveFailoverPolicy.PostFailover(ctx, FailoverResult{NodeId: ns.Id(), Err: err})
 			// 放到另一个线程做，避免死锁
 			go ns.AdvanceFSM(cs, CMD_FAILOVER_END_SIGNAL)
 		} else {
-			go cs.RunFailoverTask(ns.Id(), masterId)
+			go func() {
+				excluded := map[string]bool{}
+				var lastErr error
+				for {
+					// RunFailoverTask内部驱动WAIT_FAILOVER_END->OFFLINE迁移，这个迁移
+					// 对应的LogStateEnter会在RunFailoverTask返回之前就把pendingOutcome
+					// 取走，所以这里必须在调用之前就乐观地记下本次候选，而不能等调用
+					// 返回后再记，否则LogStateEnter拿到的永远是上一次遗留的默认"applied"。
+					// 如果这次尝试失败，本次FSM不会走到OFFLINE，这个值不会被消费，
+					// 下一次重试会在自己的RunFailoverTask之前把它覆盖掉
+					RecordFailoverOutcome(ns.Id(), fmt.Sprintf("promoted %s", candidate))
+					lastErr = cs.RunFailoverTask(ns.Id(), candidate)
+					if lastErr == nil {
+						ActiveFailoverPolicy.PostFailover(ctx, FailoverResult{
+							NodeId:      ns.Id(),
+							CandidateId: candidate,
+							Success:     true,
+						})
+						return
+					}
+					log.Printf("Promote candidate %s for %s failed, trying next: %v\n",
+						candidate, ns.Id(), lastErr)
+					excluded[candidate] = true
+
+					next, err := ActiveFailoverPolicy.SelectPromotionCandidate(ctx, excluded)
+					if err != nil {
+						break
+					}
+					candidate = next
+				}
+				RecordFailoverOutcome(ns.Id(), fmt.Sprintf("failed: all candidates exhausted: %v", lastErr))
+				ActiveFailoverPolicy.PostFailover(ctx, FailoverResult{NodeId: ns.Id(), Err: lastErr})
+			}()
 		}
 	}
 )
@@ -221,7 +300,10 @@ func init() {
 		Input:      Input{ANY, ANY, FINE, ANY, ANY},
 		Priority:   0,
 		Constraint: nil,
-		Apply:      nil,
+		Apply: func(i interface{}) {
+			ctx := i.(StateContext)
+			ClearConfirmDeadCache(ctx.NodeState.Id())
+		},
 	})
 
 	// (b1) 主节点，Autofailover或手动继续执行Failover
@@ -254,6 +336,20 @@ func init() {
 		Apply:      nil,
 	})
 
+	// (b4) 手动中止Failover（failover-abort），强制回到RUNNING，并清除宽限期和分布式确认缓存
+	RedisNodeStateModel.AddTransition(&fsm.Transition{
+		From:       StateWaitFailoverBegin,
+		To:         StateRunning,
+		Input:      Input{ANY, ANY, ANY, ANY, CMD_FAILOVER_ABORT_SIGNAL},
+		Priority:   2,
+		Constraint: nil,
+		Apply: func(i interface{}) {
+			ctx := i.(StateContext)
+			ClearEmergencyOperationGracefulPeriod(ctx.NodeState.Id())
+			ClearConfirmDeadCache(ctx.NodeState.Id())
+		},
+	})
+
 	/// State: (WaitFailoverEnd)
 
 	// (c0) 等待Failover执行结束信号